@@ -0,0 +1,56 @@
+package syncmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec controls how a SynchronisedMap is serialised by Bytes and
+// deserialised by Merge/MergeWith.  Implementations must round-trip a
+// map[T]U faithfully; New defaults to GobCodec, preserving the prior
+// behaviour of Bytes/Merge.
+type Codec[T ComparableAndOrdered, U any] interface {
+	Encode(m map[T]U) ([]byte, error)
+	Decode(b []byte) (map[T]U, error)
+}
+
+// GobCodec encodes using encoding/gob, the format historically used by
+// Bytes/Merge.
+type GobCodec[T ComparableAndOrdered, U any] struct{}
+
+// Encode serialises m using encoding/gob
+func (GobCodec[T, U]) Encode(m map[T]U) ([]byte, error) {
+	b := new(bytes.Buffer)
+	if err := gob.NewEncoder(b).Encode(m); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decode deserialises b, previously produced by Encode, using encoding/gob
+func (GobCodec[T, U]) Decode(b []byte) (map[T]U, error) {
+	m := map[T]U{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// JSONCodec encodes using encoding/json, for interoperability with
+// non-Go consumers
+type JSONCodec[T ComparableAndOrdered, U any] struct{}
+
+// Encode serialises m using encoding/json
+func (JSONCodec[T, U]) Encode(m map[T]U) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Decode deserialises b, previously produced by Encode, using encoding/json
+func (JSONCodec[T, U]) Decode(b []byte) (map[T]U, error) {
+	m := map[T]U{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
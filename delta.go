@@ -0,0 +1,218 @@
+package syncmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+)
+
+// DefaultTombstoneGCHorizon is used when WithNodeID is in effect but no
+// explicit WithTombstoneGCHorizon is supplied.
+const DefaultTombstoneGCHorizon = 24 * time.Hour
+
+// version identifies which node last wrote an entry, and a counter that
+// increases monotonically for every write that node makes. Comparing two
+// versions of the same key tells applyDeltaLocked which side is newer,
+// for last-writer-wins conflict resolution: the higher Counter wins,
+// with NodeID as a tie-breaker so that replicas converge even if two
+// nodes race to the same counter value.
+type version struct {
+	Counter uint64
+	NodeID  string
+}
+
+// after reports whether v is newer than o, per the last-writer-wins rule
+// described on version.
+func (v version) after(o version) bool {
+	if v.Counter != o.Counter {
+		return v.Counter > o.Counter
+	}
+	return v.NodeID > o.NodeID
+}
+
+// tombstone records that a key was deleted, so the deletion can itself
+// be replicated and eventually garbage collected once it is older than
+// the map's tombstoneGCHorizon (see WithTombstoneGCHorizon).
+type tombstone struct {
+	Version version
+	At      time.Time
+}
+
+// deltaEntry pairs a value with the version that produced it, or marks
+// the key as tombstoned, for the wire format emitted by BytesSince and
+// consumed by Merge/MergeWith. Expiry round-trips any per-entry TTL set
+// via InsertWithTTL, the same way ttlEntry does for the formatTTL wire
+// format, so a custom TTL on the source node survives delta sync rather
+// than being replaced by the receiving map's default TTL.
+type deltaEntry[U any] struct {
+	Value     U
+	Version   version
+	Tombstone bool
+	Expiry    time.Time
+}
+
+// deltaPayload is the gob-encoded body that follows the formatDelta tag
+// byte: the changed entries, keyed by map key. The sender's sequence
+// number is not part of the payload; BytesSince returns it separately.
+type deltaPayload[T ComparableAndOrdered, U any] struct {
+	Entries map[T]deltaEntry[U]
+}
+
+// WithNodeID identifies this replica for version-based conflict
+// resolution (see BytesSince). It must be unique across the set of
+// replicas exchanging deltas; if not supplied, every write is tagged
+// with the zero value NodeID, which is safe for a single replica but
+// will not converge correctly if deltas from more than one untagged
+// replica are merged together.
+func WithNodeID[T ComparableAndOrdered, U any](id string) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.nodeID = id
+	}
+}
+
+// WithTombstoneGCHorizon sets how long a tombstone is retained after a
+// delete before gcTombstonesLocked discards it. A tombstone must outlive
+// the longest expected gap between replicas exchanging deltas, or a
+// replica that missed the delete may resurrect the key. If not
+// supplied, DefaultTombstoneGCHorizon is used.
+func WithTombstoneGCHorizon[T ComparableAndOrdered, U any](d time.Duration) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.tombstoneGCHorizon = d
+	}
+}
+
+// nextVersionLocked returns the version to attach to the write currently
+// being made, advancing versionSeq. Callers must hold s.lck for writing.
+func (s *SynchronisedMap[T, U]) nextVersionLocked() version {
+	return version{Counter: s.versionSeq.Add(1), NodeID: s.nodeID}
+}
+
+// recordVersionLocked attaches ver to k, marking it live, and clears any
+// tombstone for the key. Callers must hold s.lck for writing.
+func (s *SynchronisedMap[T, U]) recordVersionLocked(k T, ver version) {
+	s.versions[k] = ver
+	delete(s.tombstones, k)
+}
+
+// recordTombstoneLocked replaces k's version with a tombstone, so the
+// deletion can be replicated via BytesSince. Callers must hold s.lck for
+// writing.
+func (s *SynchronisedMap[T, U]) recordTombstoneLocked(k T, ver version) {
+	delete(s.versions, k)
+	s.tombstones[k] = tombstone{Version: ver, At: time.Now()}
+}
+
+// BytesSince serialises every entry whose version is newer than since,
+// plus tombstones for every key deleted since then, using the formatDelta
+// wire format consumed by Merge/MergeWith. The returned uint64 is this
+// node's current versionSeq, to be passed as since on the next call so
+// only what changed in between is emitted. A replica using BytesSince
+// must be created with WithNodeID so its writes carry a version distinct
+// from other replicas; see WithTombstoneGCHorizon for tombstone
+// retention.
+func (s *SynchronisedMap[T, U]) BytesSince(since uint64) ([]byte, uint64, error) {
+	s.lck.RLock()
+
+	entries := map[T]deltaEntry[U]{}
+	for k, v := range s.m {
+		ver, ok := s.versions[k]
+		if !ok || ver.Counter <= since {
+			continue
+		}
+		entries[k] = deltaEntry[U]{Value: v, Version: ver, Expiry: s.expiry[k]}
+	}
+	for k, ts := range s.tombstones {
+		if ts.Version.Counter <= since {
+			continue
+		}
+		entries[k] = deltaEntry[U]{Version: ts.Version, Tombstone: true}
+	}
+	now := s.versionSeq.Load()
+
+	s.lck.RUnlock()
+
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(deltaPayload[T, U]{Entries: entries}); err != nil {
+		return nil, 0, err
+	}
+	return append([]byte{formatDelta}, buf.Bytes()...), now, nil
+}
+
+// applyDeltaLocked decodes payload, produced by BytesSince, and applies
+// each entry using last-writer-wins by version: an incoming entry is
+// only applied if no version is recorded for the key, or the incoming
+// version is after the recorded one (see version.after). resolve is not
+// consulted, since version order rather than caller-supplied logic
+// determines the winner. Callers must hold s.lck for writing.
+func (s *SynchronisedMap[T, U]) applyDeltaLocked(payload []byte) ([]Event[T, U], error) {
+	var decoded deltaPayload[T, U]
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	var events []Event[T, U]
+
+	for k, incoming := range decoded.Entries {
+		current, haveVersion := s.versions[k]
+		currentTomb, haveTomb := s.tombstones[k]
+
+		var currentVer version
+		switch {
+		case haveVersion:
+			currentVer = current
+		case haveTomb:
+			currentVer = currentTomb.Version
+		}
+
+		if (haveVersion || haveTomb) && !incoming.Version.after(currentVer) {
+			continue
+		}
+
+		if incoming.Tombstone {
+			old, existed := s.m[k]
+			if existed {
+				delete(s.m, k)
+				delete(s.expiry, k)
+				events = append(events, s.eventLocked(OpRemove, k, old, old))
+			}
+			s.recordTombstoneLocked(k, incoming.Version)
+			continue
+		}
+
+		old, existed := s.m[k]
+		s.m[k] = incoming.Value
+		if incoming.Expiry.IsZero() {
+			delete(s.expiry, k)
+		} else {
+			s.expiry[k] = incoming.Expiry
+		}
+		s.recordVersionLocked(k, incoming.Version)
+
+		if existed {
+			events = append(events, s.eventLocked(OpUpdate, k, old, incoming.Value))
+		} else {
+			events = append(events, s.eventLocked(OpInsert, k, old, incoming.Value))
+		}
+	}
+
+	s.gcTombstonesLocked()
+
+	return events, nil
+}
+
+// gcTombstonesLocked discards tombstones older than the map's
+// tombstoneGCHorizon (DefaultTombstoneGCHorizon if unset). Callers must
+// hold s.lck for writing.
+func (s *SynchronisedMap[T, U]) gcTombstonesLocked() {
+	horizon := s.tombstoneGCHorizon
+	if horizon <= 0 {
+		horizon = DefaultTombstoneGCHorizon
+	}
+
+	now := time.Now()
+	for k, ts := range s.tombstones {
+		if now.Sub(ts.At) > horizon {
+			delete(s.tombstones, k)
+		}
+	}
+}
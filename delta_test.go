@@ -0,0 +1,181 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncMapBytesSinceIncludesInitEntries(t *testing.T) {
+	c1 := New(map[string]int{"a": 1}, WithNodeID[string, int]("n1"))
+
+	b, _, err := c1.BytesSince(0)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+
+	c2 := New(map[string]int{})
+	if err := c2.Merge(b); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	if v, err := c2.Get("a"); err != nil || v != 1 {
+		t.Fatalf("expected init entry to replicate, got (%v, %v)", v, err)
+	}
+}
+
+func TestSyncMapBytesSinceIncremental(t *testing.T) {
+	c1 := New(map[string]int{}, WithNodeID[string, int]("n1"))
+
+	c1.Insert("a", 1, false)
+	b1, seq1, err := c1.BytesSince(0)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+
+	c1.Insert("b", 2, false)
+	b2, seq2, err := c1.BytesSince(seq1)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+	if seq2 <= seq1 {
+		t.Fatalf("expected second sync point (%v) to advance past the first (%v)", seq2, seq1)
+	}
+
+	c2 := New(map[string]int{})
+	if err := c2.Merge(b1); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+	if c2.Contains("b") {
+		t.Fatal("expected b to be absent before the incremental delta is merged")
+	}
+
+	if err := c2.Merge(b2); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	expected := "map[a:1 b:2]"
+	if c2.String() != expected {
+		t.Fatalf("mismatch: expected %q, got %q", expected, c2)
+	}
+}
+
+func TestSyncMapDeltaLastWriterWins(t *testing.T) {
+	c1 := New(map[string]int{}, WithNodeID[string, int]("n1"))
+	c2 := New(map[string]int{}, WithNodeID[string, int]("n2"))
+
+	c1.Insert("a", 1, false)
+	b1, _, err := c1.BytesSince(0)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+	if err := c2.Merge(b1); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	// c2's later write should win when replicated back to c1, since its
+	// version is newer, regardless of what resolve would otherwise do.
+	c2.Insert("a", 2, false)
+	b2, _, err := c2.BytesSince(0)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+	if err := c1.MergeWith(b2, func(k string, existing, incoming int) int { return existing }); err != nil {
+		t.Fatalf("unexpected error during MergeWith(): %v", err)
+	}
+
+	if v, _ := c1.Get("a"); v != 2 {
+		t.Fatalf("expected newer version to win, got %v", v)
+	}
+
+	// Replaying the stale b1 delta against c1 must not resurrect the
+	// older value, since its version is now behind what c1 holds.
+	if err := c1.Merge(b1); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+	if v, _ := c1.Get("a"); v != 2 {
+		t.Fatalf("expected stale delta to be ignored, got %v", v)
+	}
+}
+
+func TestSyncMapDeltaTombstone(t *testing.T) {
+	c1 := New(map[string]int{}, WithNodeID[string, int]("n1"))
+	c1.Insert("a", 1, false)
+	b1, seq1, err := c1.BytesSince(0)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+
+	c2 := New(map[string]int{}, WithNodeID[string, int]("n2"))
+	if err := c2.Merge(b1); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	c1.Remove("a")
+	b2, _, err := c1.BytesSince(seq1)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+
+	if err := c2.Merge(b2); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+	if c2.Contains("a") {
+		t.Fatal("expected tombstone to delete the key on the receiving replica")
+	}
+
+	// Replaying the original insert delta must not resurrect the key,
+	// since the tombstone's version is newer.
+	if err := c2.Merge(b1); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+	if c2.Contains("a") {
+		t.Fatal("expected stale insert delta to lose to the recorded tombstone")
+	}
+}
+
+func TestSyncMapDeltaPreservesPerEntryTTL(t *testing.T) {
+	c1 := New(map[string]int{}, WithDefaultTTL[string, int](time.Hour), WithNodeID[string, int]("n1"))
+	c1.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+
+	b, _, err := c1.BytesSince(0)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+
+	// The receiving map's own default TTL (an hour) must not override
+	// the shorter TTL carried on the wire from the source node.
+	c2 := New(map[string]int{}, WithDefaultTTL[string, int](time.Hour))
+	if err := c2.Merge(b); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c2.Get("a"); err == nil {
+		t.Fatal("expected the source node's short TTL to still be enforced after delta merge")
+	}
+}
+
+func TestSyncMapDeltaTombstoneGC(t *testing.T) {
+	c1 := New(map[string]int{"a": 1}, WithNodeID[string, int]("n1"), WithTombstoneGCHorizon[string, int](10*time.Millisecond))
+
+	c1.Remove("a")
+	time.Sleep(20 * time.Millisecond)
+
+	// Applying any delta runs gcTombstonesLocked, discarding the now
+	// stale tombstone.
+	b, _, err := c1.BytesSince(0)
+	if err != nil {
+		t.Fatalf("unexpected error during BytesSince(): %v", err)
+	}
+	if err := c1.Merge(b); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	c1.lck.RLock()
+	_, tombstoned := c1.tombstones["a"]
+	c1.lck.RUnlock()
+
+	if tombstoned {
+		t.Fatal("expected tombstone to be garbage collected past its horizon")
+	}
+}
@@ -0,0 +1,241 @@
+package syncmap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultShardCount is used by NewSharded when the caller requests a
+// shard count of zero (or less), and is a reasonable default for
+// spreading lock contention across many goroutines.
+const DefaultShardCount = 32
+
+// Hasher maps a key of type T to a uint64, used to select the shard
+// that owns that key within a ShardedMap
+type Hasher[T ComparableAndOrdered] func(k T) uint64
+
+// defaultHasher hashes the fmt.Sprint representation of the key with
+// FNV-1a.  This works for any ComparableAndOrdered type, including the
+// string keys that ShardedMap is most commonly used with; callers with
+// a more efficient or more appropriate hash for their key type can
+// supply their own via NewSharded
+func defaultHasher[T ComparableAndOrdered]() Hasher[T] {
+	return func(k T) uint64 {
+		h := fnv.New64a()
+		io.WriteString(h, fmt.Sprint(k))
+		return h.Sum64()
+	}
+}
+
+// shard is a single partition of a ShardedMap, guarded by its own lock
+// so that operations against different shards do not contend
+type shard[T ComparableAndOrdered, U any] struct {
+	lck sync.RWMutex
+	m   map[T]U
+}
+
+// ShardedMap provides a concurrency safe map, partitioned across a
+// fixed number of shards to reduce lock contention compared to
+// SynchronisedMap, whose single sync.RWMutex becomes a bottleneck
+// under heavy concurrent use.  It provides the Insert, Get, Contains,
+// Remove, Len, GetKeys, String, Bytes and Merge methods of
+// SynchronisedMap; the atomic, iteration, TTL, watch and versioned delta
+// methods of SynchronisedMap are not provided.
+type ShardedMap[T ComparableAndOrdered, U any] struct {
+	shards []*shard[T, U]
+	hasher Hasher[T]
+	length atomic.Int64
+}
+
+// NewSharded returns an instance of ShardedMap, containing the contents
+// of the init map, partitioned across shardCount shards.  If shardCount
+// is not greater than zero, DefaultShardCount is used.  An optional
+// hasher may be supplied to control how keys are assigned to shards;
+// if omitted, a FNV-1a hash of fmt.Sprint(k) is used.
+func NewSharded[T ComparableAndOrdered, U any](init map[T]U, shardCount int, hasher ...Hasher[T]) *ShardedMap[T, U] {
+	if shardCount <= 0 {
+		shardCount = DefaultShardCount
+	}
+
+	h := defaultHasher[T]()
+	if len(hasher) > 0 && hasher[0] != nil {
+		h = hasher[0]
+	}
+
+	s := &ShardedMap[T, U]{
+		shards: make([]*shard[T, U], shardCount),
+		hasher: h,
+	}
+	for i := range s.shards {
+		s.shards[i] = &shard[T, U]{m: map[T]U{}}
+	}
+
+	for k, v := range init {
+		s.Insert(k, v, false)
+	}
+
+	return s
+}
+
+func (s *ShardedMap[T, U]) shardFor(k T) *shard[T, U] {
+	return s.shards[s.hasher(k)%uint64(len(s.shards))]
+}
+
+// Insert adds the value at the specified key.
+// If errIfExists is true and the key exists, then an error is raised.  Otherwise
+// the value is inserted at the key, and any pre-existing value returned.
+func (s *ShardedMap[T, U]) Insert(k T, v U, errIfExists bool) (U, error) {
+	sh := s.shardFor(k)
+
+	sh.lck.Lock()
+	defer sh.lck.Unlock()
+
+	var r U
+	old, ok := sh.m[k]
+	if !ok {
+		sh.m[k] = v
+		s.length.Add(1)
+		return r, nil
+	}
+
+	if errIfExists {
+		return r, ErrKeyExists
+	}
+
+	sh.m[k] = v
+	return old, nil
+}
+
+// GetKeys returns the keys, sorted, within the map
+func (s *ShardedMap[T, U]) GetKeys() []T {
+	keys := make([]T, 0, s.Len())
+	for _, sh := range s.shards {
+		sh.lck.RLock()
+		for k := range sh.m {
+			keys = append(keys, k)
+		}
+		sh.lck.RUnlock()
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// Contains returns true if the key is found
+func (s *ShardedMap[T, U]) Contains(id T) bool {
+	sh := s.shardFor(id)
+
+	sh.lck.RLock()
+	defer sh.lck.RUnlock()
+
+	_, ok := sh.m[id]
+	return ok
+}
+
+// Get returns the value associated with the key,
+// or a key missing error
+func (s *ShardedMap[T, U]) Get(id T) (U, error) {
+	sh := s.shardFor(id)
+
+	sh.lck.RLock()
+	defer sh.lck.RUnlock()
+
+	if t, ok := sh.m[id]; ok {
+		return t, nil
+	}
+
+	var r U
+	return r, ErrMissingKey
+}
+
+// Remove deletes the key from the map
+func (s *ShardedMap[T, U]) Remove(id T) {
+	sh := s.shardFor(id)
+
+	sh.lck.Lock()
+	defer sh.lck.Unlock()
+
+	if _, ok := sh.m[id]; ok {
+		delete(sh.m, id)
+		s.length.Add(-1)
+	}
+}
+
+// Len returns the current length
+func (s *ShardedMap[T, U]) Len() int {
+	return int(s.length.Load())
+}
+
+func (s *ShardedMap[T, U]) snap() map[T]U {
+	m := make(map[T]U, s.Len())
+	for _, sh := range s.shards {
+		sh.lck.RLock()
+		for k, v := range sh.m {
+			m[k] = v
+		}
+		sh.lck.RUnlock()
+	}
+	return m
+}
+
+// String returns the contents of the map as a string,
+// with entries ordered based on the key type T
+func (s *ShardedMap[T, U]) String() string {
+
+	m := s.snap()
+
+	// Apply ordering so the output is deterministic
+	keys := SortedKeys[T, U](m)
+	buf := new(bytes.Buffer)
+	io.WriteString(buf, "map[")
+	for i, key := range keys {
+		io.WriteString(buf, fmt.Sprint(key))
+		io.WriteString(buf, ":")
+		io.WriteString(buf, fmt.Sprint(m[key]))
+		if i < len(keys)-1 {
+			io.WriteString(buf, " ")
+		}
+	}
+	io.WriteString(buf, "]")
+
+	return buf.String()
+}
+
+// Bytes serialises the current contents of the map
+func (s *ShardedMap[T, U]) Bytes() ([]byte, error) {
+
+	b := new(bytes.Buffer)
+	enc := gob.NewEncoder(b)
+
+	if err := enc.Encode(s.snap()); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Merge attempts to decode the slice, assuming it is of the
+// same type as returned by Bytes().  If successful, then
+// adds any missing key/value pairs into this instance of the map.
+func (s *ShardedMap[T, U]) Merge(b []byte) error {
+	buf := new(bytes.Buffer)
+	buf.Write(b)
+	dec := gob.NewDecoder(buf)
+
+	m := map[T]U{}
+
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+
+	for k, v := range m {
+		s.Insert(k, v, true)
+	}
+
+	return nil
+}
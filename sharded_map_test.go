@@ -0,0 +1,110 @@
+package syncmap
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestShardedMap(t *testing.T) {
+	m := NewSharded(map[string]int{}, 0)
+
+	var wg sync.WaitGroup
+	var N int = 10000
+
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			m.Insert(fmt.Sprint(n), n, false)
+		}(i)
+	}
+
+	wg.Wait()
+
+	if m.Len() != N {
+		t.Fatalf("mismatched count: expected %v, got %v\n", N, m.Len())
+	}
+}
+
+func TestShardedMapGet(t *testing.T) {
+	m := NewSharded(map[string]int{"a": 1}, 4)
+
+	if v, err := m.Get("a"); err != nil || v != 1 {
+		t.Fatalf("unexpected result (%v, %v)", v, err)
+	}
+
+	if _, err := m.Get("c"); err == nil {
+		t.Fatal("expected error but none returned")
+	}
+}
+
+func TestShardedMapContains(t *testing.T) {
+	m := NewSharded(map[string]int{"a": 1}, 4)
+
+	if !m.Contains("a") {
+		t.Fatal("returned not found when expected to find")
+	}
+	if m.Contains("b") {
+		t.Fatal("returned found when expected not to find")
+	}
+}
+
+func TestShardedMapGetKeys(t *testing.T) {
+	m := NewSharded(map[string]int{"c": 1, "b": 2, "a": 3}, 4)
+	keys := m.GetKeys()
+	if fmt.Sprint(keys) != fmt.Sprint([]string{"a", "b", "c"}) {
+		t.Fatalf("unexpected keys returned (%v)", keys)
+	}
+}
+
+func TestShardedMapDelete(t *testing.T) {
+	m := NewSharded(map[string]int{"c": 1, "b": 2, "a": 3}, 4)
+
+	m.Remove("a")
+	m.Remove("c")
+	m.Remove("aa")
+
+	if fmt.Sprint(m) != "map[b:2]" {
+		t.Fatalf("unexpected post deletion state (%v)", m)
+	}
+	if m.Len() != 1 {
+		t.Fatalf("unexpected length (%v)", m.Len())
+	}
+}
+
+func TestShardedMapBytes(t *testing.T) {
+	c1 := NewSharded(map[string]int{"a": 1, "b": -1}, 4)
+	b, err := c1.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error during Bytes(): %v", err)
+	}
+
+	c2 := NewSharded(map[string]int{"c": -3}, 4)
+
+	if err := c2.Merge(b); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	expected := "map[a:1 b:-1 c:-3]"
+	if c2.String() != expected {
+		t.Fatalf("mismatch: expected %q, got %q", expected, c2)
+	}
+}
+
+func TestShardedMapCustomHasher(t *testing.T) {
+	calls := 0
+	hasher := func(k string) uint64 {
+		calls++
+		return uint64(len(k))
+	}
+
+	m := NewSharded(map[string]int{"a": 1, "bb": 2}, 4, hasher)
+
+	if v, err := m.Get("bb"); err != nil || v != 2 {
+		t.Fatalf("unexpected result (%v, %v)", v, err)
+	}
+	if calls == 0 {
+		t.Fatal("expected custom hasher to be invoked")
+	}
+}
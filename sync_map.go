@@ -7,8 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // SortedKeys returns a sorted slice of the map's keys
@@ -29,15 +33,61 @@ type ComparableAndOrdered interface {
 	cmp.Ordered
 }
 
+// Option configures a SynchronisedMap at construction time, via New
+type Option[T ComparableAndOrdered, U any] func(*SynchronisedMap[T, U])
+
+// WithEqual supplies the equality function used by CompareAndSwap and
+// CompareAndDelete to decide whether a value matches the caller's
+// expected old value.  Since U is any, and not comparable, == cannot be
+// used; if this option is not supplied, reflect.DeepEqual is used.
+func WithEqual[T ComparableAndOrdered, U any](eq func(a, b U) bool) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.eq = eq
+	}
+}
+
+// WithCodec selects the Codec used by Bytes and Merge/MergeWith to
+// serialise and deserialise the map's contents.  If not supplied,
+// GobCodec is used, preserving the historic behaviour of Bytes/Merge.
+func WithCodec[T ComparableAndOrdered, U any](codec Codec[T, U]) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.codec = codec
+	}
+}
+
 // New returns an instance of SynchronisedMap, containing the
 // contents of the init map
-func New[T ComparableAndOrdered, U any](init map[T]U) *SynchronisedMap[T, U] {
+func New[T ComparableAndOrdered, U any](init map[T]U, opts ...Option[T, U]) *SynchronisedMap[T, U] {
 	m := &SynchronisedMap[T, U]{
-		m: map[T]U{},
+		m:          map[T]U{},
+		expiry:     map[T]time.Time{},
+		watchers:   map[<-chan Event[T, U]]*subscriber[T, U]{},
+		versions:   map[T]version{},
+		tombstones: map[T]tombstone{},
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.eq == nil {
+		m.eq = func(a, b U) bool { return reflect.DeepEqual(a, b) }
+	}
+	if m.codec == nil {
+		m.codec = GobCodec[T, U]{}
+	}
+	if m.watchBufferSize <= 0 {
+		m.watchBufferSize = DefaultWatchBufferSize
 	}
 
 	for k, v := range init {
 		m.m[k] = v
+		m.setExpiryLocked(k, m.defaultTTL)
+		m.recordVersionLocked(k, m.nextVersionLocked())
+	}
+
+	if m.defaultTTL > 0 {
+		m.startReaper()
 	}
 
 	return m
@@ -51,29 +101,62 @@ var ErrKeyExists = errors.New("key already exists")
 
 // SynchronisedMap provides a concurrency safe map
 type SynchronisedMap[T ComparableAndOrdered, U any] struct {
-	lck sync.RWMutex
-	m   map[T]U
+	lck   sync.RWMutex
+	m     map[T]U
+	eq    func(a, b U) bool
+	codec Codec[T, U]
+
+	expiry       map[T]time.Time
+	defaultTTL   time.Duration
+	reapInterval time.Duration
+	onExpire     func(k T, v U)
+	reaperStop   chan struct{}
+	reaperDone   chan struct{}
+
+	seq             atomic.Uint64
+	watchLck        sync.RWMutex
+	watchers        map[<-chan Event[T, U]]*subscriber[T, U]
+	watchPolicy     WatchPolicy
+	watchBufferSize int
+
+	nodeID             string
+	versionSeq         atomic.Uint64
+	versions           map[T]version
+	tombstones         map[T]tombstone
+	tombstoneGCHorizon time.Duration
 }
 
 // Insert adds the value at the specified key.
 // If errIfExists is true and the key exists, then an error is raised.  Otherwise
 // the value is inserted at the key, and any pre-existing value returned.
+// If the map was created with WithDefaultTTL, the entry expires after that
+// duration; see InsertWithTTL to set or override the TTL per entry.
 func (s *SynchronisedMap[T, U]) Insert(k T, v U, errIfExists bool) (U, error) {
 	s.lck.Lock()
-	defer s.lck.Unlock()
 
 	var r U
 	old, ok := s.m[k]
 	if !ok {
 		s.m[k] = v
+		s.setExpiryLocked(k, s.defaultTTL)
+		s.recordVersionLocked(k, s.nextVersionLocked())
+		ev := s.eventLocked(OpInsert, k, r, v)
+		s.lck.Unlock()
+		s.notify(ev)
 		return r, nil
 	}
 
 	if errIfExists {
+		s.lck.Unlock()
 		return r, ErrKeyExists
 	}
 
 	s.m[k] = v
+	s.setExpiryLocked(k, s.defaultTTL)
+	s.recordVersionLocked(k, s.nextVersionLocked())
+	ev := s.eventLocked(OpUpdate, k, old, v)
+	s.lck.Unlock()
+	s.notify(ev)
 	return old, nil
 }
 
@@ -85,24 +168,36 @@ func (s *SynchronisedMap[T, U]) GetKeys() []T {
 	return SortedKeys(s.m)
 }
 
-// Contains returns true if the key is found
+// Contains returns true if the key is found.  A key whose TTL has
+// elapsed is treated as not found, and is lazily reaped.
 func (s *SynchronisedMap[T, U]) Contains(id T) bool {
 	s.lck.RLock()
-	defer s.lck.RUnlock()
-
 	_, ok := s.m[id]
+	expired := ok && s.isExpiredLocked(id)
+	s.lck.RUnlock()
+
+	if expired {
+		s.reapKey(id)
+		return false
+	}
 	return ok
 }
 
 // Get returns the value associated with the key,
-// or a key missing error
+// or a key missing error.  A key whose TTL has elapsed is treated as
+// missing, and is lazily reaped.
 func (s *SynchronisedMap[T, U]) Get(id T) (U, error) {
 	s.lck.RLock()
-	defer s.lck.RUnlock()
+	t, ok := s.m[id]
+	expired := ok && s.isExpiredLocked(id)
+	s.lck.RUnlock()
 
-	if t, ok := s.m[id]; ok {
+	if ok && !expired {
 		return t, nil
 	}
+	if expired {
+		s.reapKey(id)
+	}
 
 	var r U
 	return r, ErrMissingKey
@@ -111,9 +206,148 @@ func (s *SynchronisedMap[T, U]) Get(id T) (U, error) {
 // Remove deletes the key from the map
 func (s *SynchronisedMap[T, U]) Remove(id T) {
 	s.lck.Lock()
-	defer s.lck.Unlock()
+
+	old, ok := s.m[id]
+	if !ok {
+		s.lck.Unlock()
+		return
+	}
 
 	delete(s.m, id)
+	delete(s.expiry, id)
+	s.recordTombstoneLocked(id, s.nextVersionLocked())
+	ev := s.eventLocked(OpRemove, id, old, old)
+	s.lck.Unlock()
+	s.notify(ev)
+}
+
+// Swap sets the value at the specified key, returning the value it
+// replaced, and whether a value was actually present beforehand. A key
+// whose TTL has elapsed is treated as not present, the same as Get.
+func (s *SynchronisedMap[T, U]) Swap(k T, v U) (U, bool) {
+	s.lck.Lock()
+
+	old, loaded, expireEv := s.loadLiveLocked(k)
+
+	s.m[k] = v
+	s.setExpiryLocked(k, s.defaultTTL)
+	s.recordVersionLocked(k, s.nextVersionLocked())
+
+	op := OpInsert
+	if loaded {
+		op = OpUpdate
+	}
+	ev := s.eventLocked(op, k, old, v)
+	s.lck.Unlock()
+
+	s.fireExpire(expireEv)
+	s.notify(ev)
+
+	return old, loaded
+}
+
+// CompareAndSwap sets the value at the specified key to new, but only
+// if the existing value is equal (per the map's equality function, see
+// WithEqual) to old. It reports whether the swap took place. A key whose
+// TTL has elapsed is treated as not present, the same as Get.
+func (s *SynchronisedMap[T, U]) CompareAndSwap(k T, old, new U) bool {
+	s.lck.Lock()
+
+	existing, loaded, expireEv := s.loadLiveLocked(k)
+	if !loaded || !s.eq(existing, old) {
+		s.lck.Unlock()
+		s.fireExpire(expireEv)
+		return false
+	}
+
+	s.m[k] = new
+	s.setExpiryLocked(k, s.defaultTTL)
+	s.recordVersionLocked(k, s.nextVersionLocked())
+	ev := s.eventLocked(OpUpdate, k, existing, new)
+	s.lck.Unlock()
+
+	s.fireExpire(expireEv)
+	s.notify(ev)
+
+	return true
+}
+
+// CompareAndDelete removes the entry at the specified key, but only if
+// the existing value is equal (per the map's equality function, see
+// WithEqual) to old. It reports whether the delete took place. A key
+// whose TTL has elapsed is treated as not present, the same as Get.
+func (s *SynchronisedMap[T, U]) CompareAndDelete(k T, old U) bool {
+	s.lck.Lock()
+
+	existing, loaded, expireEv := s.loadLiveLocked(k)
+	if !loaded || !s.eq(existing, old) {
+		s.lck.Unlock()
+		s.fireExpire(expireEv)
+		return false
+	}
+
+	delete(s.m, k)
+	delete(s.expiry, k)
+	s.recordTombstoneLocked(k, s.nextVersionLocked())
+	ev := s.eventLocked(OpRemove, k, existing, existing)
+	s.lck.Unlock()
+
+	s.fireExpire(expireEv)
+	s.notify(ev)
+
+	return true
+}
+
+// LoadOrStore returns the existing value at the specified key if present.
+// Otherwise, it stores and returns v. The loaded result is true if the
+// value was already present. A key whose TTL has elapsed is treated as
+// not present, the same as Get, so it is overwritten with v rather than
+// returned.
+func (s *SynchronisedMap[T, U]) LoadOrStore(k T, v U) (U, bool) {
+	s.lck.Lock()
+
+	existing, loaded, expireEv := s.loadLiveLocked(k)
+	if loaded {
+		s.lck.Unlock()
+		s.fireExpire(expireEv)
+		return existing, true
+	}
+
+	s.m[k] = v
+	s.setExpiryLocked(k, s.defaultTTL)
+	s.recordVersionLocked(k, s.nextVersionLocked())
+	ev := s.eventLocked(OpInsert, k, v, v)
+	s.lck.Unlock()
+
+	s.fireExpire(expireEv)
+	s.notify(ev)
+
+	return v, false
+}
+
+// LoadAndDelete removes the value at the specified key, returning the
+// value it held, and whether it was present beforehand. A key whose TTL
+// has elapsed is treated as not present, the same as Get.
+func (s *SynchronisedMap[T, U]) LoadAndDelete(k T) (U, bool) {
+	s.lck.Lock()
+
+	v, loaded, expireEv := s.loadLiveLocked(k)
+	if !loaded {
+		s.lck.Unlock()
+		s.fireExpire(expireEv)
+		return v, false
+	}
+
+	delete(s.m, k)
+	delete(s.expiry, k)
+	s.recordTombstoneLocked(k, s.nextVersionLocked())
+	ev := s.eventLocked(OpRemove, k, v, v)
+	s.lck.Unlock()
+
+	s.fireExpire(expireEv)
+	s.notify(ev)
+
+	return v, true
 }
 
 // Len returns the current length
@@ -124,6 +358,32 @@ func (s *SynchronisedMap[T, U]) Len() int {
 	return len(s.m)
 }
 
+// Range calls f sequentially for each key/value pair in the map, over a
+// snapshot taken at the start of the call; mutations made by other
+// goroutines during iteration are neither guaranteed to be seen nor to
+// be missed.  Iteration stops early if f returns false.
+func (s *SynchronisedMap[T, U]) Range(f func(k T, v U) bool) {
+	for k, v := range s.snap() {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the key/value pairs in the map, taken as
+// a snapshot at the start of iteration, for use with range-over-func.
+// As with Range, mutations made by other goroutines during iteration are
+// neither guaranteed to be seen nor to be missed.
+func (s *SynchronisedMap[T, U]) All() iter.Seq2[T, U] {
+	return func(yield func(T, U) bool) {
+		for k, v := range s.snap() {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
 func (s *SynchronisedMap[T, U]) snap() map[T]U {
 	s.lck.RLock()
 	defer s.lck.RUnlock()
@@ -159,39 +419,145 @@ func (s *SynchronisedMap[T, U]) String() string {
 	return buf.String()
 }
 
-// Bytes serialises the current contents of the map
+// ttlEntry pairs a value with its expiry, so that Bytes/Merge can round
+// trip TTLs between SynchronisedMap instances
+type ttlEntry[U any] struct {
+	Value  U
+	Expiry time.Time
+}
+
+// wire format tags, prefixed to the output of Bytes so Merge/MergeWith
+// can tell which shape follows, independent of whether the receiving
+// map has TTLs configured
+const (
+	formatPlain byte = iota
+	formatTTL
+	formatDelta
+)
+
+// Bytes serialises the current contents of the map, using the map's
+// Codec (GobCodec by default, see WithCodec).  If any entry carries a
+// TTL, expiry timestamps are also captured so that Merge/MergeWith can
+// restore them; in that case the payload is always gob-encoded,
+// irrespective of the configured Codec, since expiry is internal
+// bookkeeping rather than part of the user-visible value type.
 func (s *SynchronisedMap[T, U]) Bytes() ([]byte, error) {
+	s.lck.RLock()
+	hasTTL := len(s.expiry) > 0
+	if !hasTTL {
+		m := make(map[T]U, len(s.m))
+		for k, v := range s.m {
+			m[k] = v
+		}
+		s.lck.RUnlock()
 
-	b := new(bytes.Buffer)
-	enc := gob.NewEncoder(b)
+		b, err := s.codec.Encode(m)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{formatPlain}, b...), nil
+	}
+
+	entries := make(map[T]ttlEntry[U], len(s.m))
+	for k, v := range s.m {
+		entries[k] = ttlEntry[U]{Value: v, Expiry: s.expiry[k]}
+	}
+	s.lck.RUnlock()
 
-	if err := enc.Encode(s.snap()); err != nil {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(entries); err != nil {
 		return nil, err
 	}
-	return b.Bytes(), nil
+	return append([]byte{formatTTL}, buf.Bytes()...), nil
 }
 
-// Merge attempts to decode the slice, assuming it is of the
-// same type as returned by Bytes().  If successful, then
-// adds any missing key/value pairs into this instance of the map.
+// Merge attempts to decode the slice, assuming it was produced by
+// Bytes(). If successful, then adds any missing key/value pairs into
+// this instance of the map; keys already present keep their existing
+// value, although if b carries expiry timestamps their expiry is
+// replaced by the incoming one. If b was produced by BytesSince, entries
+// are applied by version instead, so an existing key may be updated or
+// deleted. For other conflict resolution strategies, see MergeWith.
 func (s *SynchronisedMap[T, U]) Merge(b []byte) error {
-	buf := new(bytes.Buffer)
-	buf.Write(b)
-	dec := gob.NewDecoder(buf)
-
-	m := map[T]U{}
+	return s.MergeWith(b, func(k T, existing, incoming U) U { return existing })
+}
 
-	if err := dec.Decode(&m); err != nil {
-		return err
+// MergeWith attempts to decode the slice, assuming it was produced by
+// Bytes(). If successful, then for every decoded key/value pair whose
+// key is not yet present, the incoming value is stored directly (with
+// the map's default TTL, if configured); for keys that already exist,
+// resolve is called with the existing and incoming values and its
+// result is stored instead. If b carries expiry timestamps (see
+// Bytes), they are restored verbatim rather than recomputed from the
+// default TTL. This allows callers to implement LWW, additive merge,
+// max/min, or other custom conflict resolution in place of the
+// "first writer wins" behaviour of Merge. If b was produced by
+// BytesSince, resolve is not consulted: entries are instead applied
+// using last-writer-wins by version, including tombstones for deletes -
+// see BytesSince.
+func (s *SynchronisedMap[T, U]) MergeWith(b []byte, resolve func(k T, existing, incoming U) U) error {
+	if len(b) == 0 {
+		return io.ErrUnexpectedEOF
 	}
 
+	format, payload := b[0], b[1:]
+
 	s.lck.Lock()
-	defer s.lck.Unlock()
 
-	for k, v := range m {
-		if _, ok := s.m[k]; !ok {
-			s.m[k] = v
+	var events []Event[T, U]
+
+	switch format {
+	case formatDelta:
+		deltaEvents, err := s.applyDeltaLocked(payload)
+		if err != nil {
+			s.lck.Unlock()
+			return err
+		}
+		events = append(events, deltaEvents...)
+	case formatTTL:
+		entries := map[T]ttlEntry[U]{}
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&entries); err != nil {
+			s.lck.Unlock()
+			return err
 		}
+		for k, incoming := range entries {
+			if existing, ok := s.m[k]; ok {
+				s.m[k] = resolve(k, existing, incoming.Value)
+				events = append(events, s.eventLocked(OpUpdate, k, existing, s.m[k]))
+			} else {
+				s.m[k] = incoming.Value
+				events = append(events, s.eventLocked(OpInsert, k, incoming.Value, incoming.Value))
+			}
+			if incoming.Expiry.IsZero() {
+				delete(s.expiry, k)
+			} else {
+				s.expiry[k] = incoming.Expiry
+			}
+			s.recordVersionLocked(k, s.nextVersionLocked())
+		}
+	default:
+		m, err := s.codec.Decode(payload)
+		if err != nil {
+			s.lck.Unlock()
+			return err
+		}
+		for k, incoming := range m {
+			if existing, ok := s.m[k]; ok {
+				s.m[k] = resolve(k, existing, incoming)
+				events = append(events, s.eventLocked(OpUpdate, k, existing, s.m[k]))
+			} else {
+				s.m[k] = incoming
+				s.setExpiryLocked(k, s.defaultTTL)
+				events = append(events, s.eventLocked(OpInsert, k, incoming, incoming))
+			}
+			s.recordVersionLocked(k, s.nextVersionLocked())
+		}
+	}
+
+	s.lck.Unlock()
+
+	for _, ev := range events {
+		s.notify(ev)
 	}
 
 	return nil
@@ -126,6 +126,157 @@ func TestSyncMapDelete(t *testing.T) {
 	}
 }
 
+func TestSyncMapSwap(t *testing.T) {
+	m := New(map[string]int{"a": 1})
+
+	old, loaded := m.Swap("a", 2)
+	if !loaded || old != 1 {
+		t.Fatalf("unexpected result (%v, %v)", old, loaded)
+	}
+
+	old, loaded = m.Swap("b", 3)
+	if loaded || old != 0 {
+		t.Fatalf("unexpected result (%v, %v)", old, loaded)
+	}
+
+	if v, _ := m.Get("b"); v != 3 {
+		t.Fatalf("unexpected value after Swap: %v", v)
+	}
+}
+
+func TestSyncMapCompareAndSwap(t *testing.T) {
+	m := New(map[string]int{"a": 1})
+
+	if m.CompareAndSwap("a", 2, 3) {
+		t.Fatal("expected CompareAndSwap to fail on mismatched old value")
+	}
+	if !m.CompareAndSwap("a", 1, 3) {
+		t.Fatal("expected CompareAndSwap to succeed on matching old value")
+	}
+	if v, _ := m.Get("a"); v != 3 {
+		t.Fatalf("unexpected value after CompareAndSwap: %v", v)
+	}
+	if m.CompareAndSwap("c", 0, 1) {
+		t.Fatal("expected CompareAndSwap to fail on missing key")
+	}
+}
+
+func TestSyncMapCompareAndDelete(t *testing.T) {
+	m := New(map[string]int{"a": 1})
+
+	if m.CompareAndDelete("a", 2) {
+		t.Fatal("expected CompareAndDelete to fail on mismatched old value")
+	}
+	if !m.CompareAndDelete("a", 1) {
+		t.Fatal("expected CompareAndDelete to succeed on matching old value")
+	}
+	if m.Contains("a") {
+		t.Fatal("expected key to be removed after CompareAndDelete")
+	}
+}
+
+func TestSyncMapLoadOrStore(t *testing.T) {
+	m := New(map[string]int{"a": 1})
+
+	v, loaded := m.LoadOrStore("a", 2)
+	if !loaded || v != 1 {
+		t.Fatalf("unexpected result (%v, %v)", v, loaded)
+	}
+
+	v, loaded = m.LoadOrStore("b", 2)
+	if loaded || v != 2 {
+		t.Fatalf("unexpected result (%v, %v)", v, loaded)
+	}
+	if got, _ := m.Get("b"); got != 2 {
+		t.Fatalf("unexpected value after LoadOrStore: %v", got)
+	}
+}
+
+func TestSyncMapLoadAndDelete(t *testing.T) {
+	m := New(map[string]int{"a": 1})
+
+	v, ok := m.LoadAndDelete("a")
+	if !ok || v != 1 {
+		t.Fatalf("unexpected result (%v, %v)", v, ok)
+	}
+	if m.Contains("a") {
+		t.Fatal("expected key to be removed after LoadAndDelete")
+	}
+
+	_, ok = m.LoadAndDelete("a")
+	if ok {
+		t.Fatal("expected missing key to report not loaded")
+	}
+}
+
+func TestSyncMapWithEqual(t *testing.T) {
+	type val struct{ n int }
+
+	m := New(map[string]val{"a": {n: 1}}, WithEqual[string, val](func(a, b val) bool { return a.n == b.n }))
+
+	if !m.CompareAndSwap("a", val{n: 1}, val{n: 2}) {
+		t.Fatal("expected CompareAndSwap to succeed using custom equality")
+	}
+	if v, _ := m.Get("a"); v.n != 2 {
+		t.Fatalf("unexpected value after CompareAndSwap: %v", v)
+	}
+}
+
+func TestSyncMapRange(t *testing.T) {
+	m := New(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	seen := map[string]int{}
+	m.Range(func(k string, v int) bool {
+		seen[k] = v
+		return true
+	})
+
+	if len(seen) != 3 || seen["a"] != 1 || seen["b"] != 2 || seen["c"] != 3 {
+		t.Fatalf("unexpected entries visited: %v", seen)
+	}
+}
+
+func TestSyncMapRangeEarlyTermination(t *testing.T) {
+	m := New(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	count := 0
+	m.Range(func(k string, v int) bool {
+		count++
+		return false
+	})
+
+	if count != 1 {
+		t.Fatalf("expected iteration to stop after first entry, visited %v", count)
+	}
+}
+
+func TestSyncMapAll(t *testing.T) {
+	m := New(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	seen := map[string]int{}
+	for k, v := range m.All() {
+		seen[k] = v
+	}
+
+	if len(seen) != 3 || seen["a"] != 1 || seen["b"] != 2 || seen["c"] != 3 {
+		t.Fatalf("unexpected entries visited: %v", seen)
+	}
+}
+
+func TestSyncMapAllEarlyTermination(t *testing.T) {
+	m := New(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	count := 0
+	for range m.All() {
+		count++
+		break
+	}
+
+	if count != 1 {
+		t.Fatalf("expected iteration to stop after first entry, visited %v", count)
+	}
+}
+
 func TestSyncMapBytes(t *testing.T) {
 
 	c1 := New(map[string]int{"a": 1, "b": -1})
@@ -188,6 +339,45 @@ func TestSyncMapBytes3(t *testing.T) {
 	}
 }
 
+func TestSyncMapJSONCodec(t *testing.T) {
+	c1 := New(map[string]int{"a": 1, "b": -1}, WithCodec[string, int](JSONCodec[string, int]{}))
+	b, err := c1.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error during Bytes(): %v", err)
+	}
+
+	c2 := New(map[string]int{"c": -3}, WithCodec[string, int](JSONCodec[string, int]{}))
+
+	if err := c2.Merge(b); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	expected := "map[a:1 b:-1 c:-3]"
+	if c2.String() != expected {
+		t.Fatalf("mismatch: expected %q, got %q", expected, c2)
+	}
+}
+
+func TestSyncMapMergeWith(t *testing.T) {
+	c1 := New(map[string]int{"a": 1, "b": 2})
+	b, err := c1.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error during Bytes(): %v", err)
+	}
+
+	c2 := New(map[string]int{"a": 10, "c": 3})
+
+	err = c2.MergeWith(b, func(k string, existing, incoming int) int { return existing + incoming })
+	if err != nil {
+		t.Fatalf("unexpected error during MergeWith(): %v", err)
+	}
+
+	expected := "map[a:11 b:2 c:3]"
+	if c2.String() != expected {
+		t.Fatalf("mismatch: expected %q, got %q", expected, c2)
+	}
+}
+
 func ExampleNew() {
 	c := New(map[string]int{"x": 0, "y": 0})
 
@@ -0,0 +1,205 @@
+package syncmap
+
+import "time"
+
+// DefaultReapInterval is used when TTLs are enabled via WithDefaultTTL
+// but no explicit WithReapInterval is supplied.
+const DefaultReapInterval = time.Minute
+
+// WithDefaultTTL sets the TTL applied to entries inserted via Insert,
+// and to keys newly added by Merge/MergeWith from a payload that carries
+// no expiry timestamps.  Payloads that carry expiry timestamps (see
+// Bytes and BytesSince) have them restored as sent, so an entry sent
+// without an expiry does not receive the default TTL.  It also enables the background reaper (see
+// WithReapInterval), which proactively removes expired entries instead
+// of leaving them to be lazily reaped on the next Get/Contains.
+func WithDefaultTTL[T ComparableAndOrdered, U any](ttl time.Duration) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.defaultTTL = ttl
+	}
+}
+
+// WithReapInterval sets how often the background reaper, started when
+// WithDefaultTTL is in effect, sweeps the map for expired entries. If
+// not supplied, DefaultReapInterval is used.
+func WithReapInterval[T ComparableAndOrdered, U any](d time.Duration) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.reapInterval = d
+	}
+}
+
+// WithOnExpire registers a callback invoked, outside the map's lock,
+// whenever an entry is reaped - whether lazily on Get/Contains, or
+// proactively by the background reaper.
+func WithOnExpire[T ComparableAndOrdered, U any](f func(k T, v U)) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.onExpire = f
+	}
+}
+
+// InsertWithTTL adds the value at the specified key, expiring it after
+// ttl.  A ttl of zero or less means the entry never expires, overriding
+// any map-wide default TTL. If errIfExists is true and the key exists,
+// then an error is raised. Otherwise the value is inserted at the key,
+// and any pre-existing value returned.
+func (s *SynchronisedMap[T, U]) InsertWithTTL(k T, v U, ttl time.Duration, errIfExists bool) (U, error) {
+	s.lck.Lock()
+
+	var r U
+	old, ok := s.m[k]
+	if !ok {
+		s.m[k] = v
+		s.setExpiryLocked(k, ttl)
+		s.recordVersionLocked(k, s.nextVersionLocked())
+		ev := s.eventLocked(OpInsert, k, r, v)
+		s.lck.Unlock()
+		s.notify(ev)
+		return r, nil
+	}
+
+	if errIfExists {
+		s.lck.Unlock()
+		return r, ErrKeyExists
+	}
+
+	s.m[k] = v
+	s.setExpiryLocked(k, ttl)
+	s.recordVersionLocked(k, s.nextVersionLocked())
+	ev := s.eventLocked(OpUpdate, k, old, v)
+	s.lck.Unlock()
+	s.notify(ev)
+	return old, nil
+}
+
+// Stop halts the background reaper, if one was started by New because
+// WithDefaultTTL was in effect. It is safe to call Stop on a map with
+// no reaper running.
+func (s *SynchronisedMap[T, U]) Stop() {
+	s.lck.Lock()
+	stop := s.reaperStop
+	done := s.reaperDone
+	s.reaperStop = nil
+	s.reaperDone = nil
+	s.lck.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// setExpiryLocked records when the entry at k should expire, given ttl.
+// A ttl of zero or less clears any expiry, meaning the entry never
+// expires. Callers must hold s.lck for writing.
+func (s *SynchronisedMap[T, U]) setExpiryLocked(k T, ttl time.Duration) {
+	if ttl <= 0 {
+		delete(s.expiry, k)
+		return
+	}
+	s.expiry[k] = time.Now().Add(ttl)
+}
+
+// isExpiredLocked reports whether the entry at k has an expiry that has
+// elapsed. Callers must hold s.lck for reading or writing.
+func (s *SynchronisedMap[T, U]) isExpiredLocked(k T) bool {
+	exp, ok := s.expiry[k]
+	return ok && time.Now().After(exp)
+}
+
+// reapKey removes k if, and only if, it is still present and still
+// expired at the time the lock is acquired, then fires OnExpire and
+// notifies watchers with an OpExpire event.
+func (s *SynchronisedMap[T, U]) reapKey(k T) {
+	s.lck.Lock()
+	_, _, expireEv := s.loadLiveLocked(k)
+	s.lck.Unlock()
+
+	s.fireExpire(expireEv)
+}
+
+// loadLiveLocked returns the value currently stored at k, treating an
+// entry whose TTL has elapsed as absent: such an entry is removed and
+// recorded as expired, the same as the lazy expiry done by Get/Contains,
+// and the resulting OpExpire event is returned for the caller to pass to
+// fireExpire once s.lck is released. Every mutator that inspects an
+// existing value before deciding whether to treat a key as present
+// (Swap, CompareAndSwap, CompareAndDelete, LoadOrStore, LoadAndDelete)
+// must call this rather than reading s.m directly, or a write can
+// silently resurrect - or be shadowed by - a value that has already
+// expired. Callers must hold s.lck for writing.
+func (s *SynchronisedMap[T, U]) loadLiveLocked(k T) (v U, loaded bool, expireEvent *Event[T, U]) {
+	existing, ok := s.m[k]
+	if !ok {
+		return v, false, nil
+	}
+	if !s.isExpiredLocked(k) {
+		return existing, true, nil
+	}
+
+	delete(s.m, k)
+	delete(s.expiry, k)
+	s.recordTombstoneLocked(k, s.nextVersionLocked())
+	ev := s.eventLocked(OpExpire, k, existing, existing)
+	return v, false, &ev
+}
+
+// fireExpire invokes OnExpire and notifies watchers for the OpExpire
+// event produced by loadLiveLocked, once s.lck has been released. It is
+// a no-op if ev is nil, i.e. loadLiveLocked did not reap anything.
+func (s *SynchronisedMap[T, U]) fireExpire(ev *Event[T, U]) {
+	if ev == nil {
+		return
+	}
+	if s.onExpire != nil {
+		s.onExpire(ev.Key, ev.Old)
+	}
+	s.notify(*ev)
+}
+
+// startReaper launches the background goroutine that proactively sweeps
+// the map for expired entries every reapInterval (DefaultReapInterval if
+// unset). It is called once, from New, when WithDefaultTTL is in effect.
+func (s *SynchronisedMap[T, U]) startReaper() {
+	interval := s.reapInterval
+	if interval <= 0 {
+		interval = DefaultReapInterval
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	s.reaperStop = stop
+	s.reaperDone = done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.sweep()
+			}
+		}
+	}()
+}
+
+// sweep removes every currently expired entry, firing OnExpire for each
+func (s *SynchronisedMap[T, U]) sweep() {
+	s.lck.RLock()
+	expired := make([]T, 0)
+	for k := range s.expiry {
+		if s.isExpiredLocked(k) {
+			expired = append(expired, k)
+		}
+	}
+	s.lck.RUnlock()
+
+	for _, k := range expired {
+		s.reapKey(k)
+	}
+}
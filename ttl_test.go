@@ -0,0 +1,174 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncMapInsertWithTTL(t *testing.T) {
+	m := New(map[string]int{})
+
+	m.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+
+	if v, err := m.Get("a"); err != nil || v != 1 {
+		t.Fatalf("unexpected result before expiry (%v, %v)", v, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := m.Get("a"); err == nil {
+		t.Fatal("expected key to have expired")
+	}
+	if m.Contains("a") {
+		t.Fatal("expected expired key to be reported as not found")
+	}
+}
+
+func TestSyncMapInsertWithTTLNoExpiry(t *testing.T) {
+	m := New(map[string]int{})
+
+	m.InsertWithTTL("a", 1, 0, false)
+	time.Sleep(10 * time.Millisecond)
+
+	if v, err := m.Get("a"); err != nil || v != 1 {
+		t.Fatalf("expected entry with no TTL to survive (%v, %v)", v, err)
+	}
+}
+
+func TestSyncMapDefaultTTL(t *testing.T) {
+	m := New(map[string]int{}, WithDefaultTTL[string, int](10*time.Millisecond))
+
+	m.Insert("a", 1, false)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := m.Get("a"); err == nil {
+		t.Fatal("expected entry to expire under the map's default TTL")
+	}
+}
+
+func TestSyncMapReaperAndOnExpire(t *testing.T) {
+	expired := make(chan string, 1)
+
+	m := New(map[string]int{},
+		WithDefaultTTL[string, int](10*time.Millisecond),
+		WithReapInterval[string, int](5*time.Millisecond),
+		WithOnExpire(func(k string, v int) { expired <- k }),
+	)
+	defer m.Stop()
+
+	m.Insert("a", 1, false)
+
+	select {
+	case k := <-expired:
+		if k != "a" {
+			t.Fatalf("unexpected key reaped: %v", k)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for background reaper to expire entry")
+	}
+
+	if m.Contains("a") {
+		t.Fatal("expected reaper to have removed the entry")
+	}
+}
+
+func TestSyncMapSwapExpired(t *testing.T) {
+	m := New(map[string]int{})
+	m.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+	time.Sleep(20 * time.Millisecond)
+
+	old, loaded := m.Swap("a", 42)
+	if loaded || old != 0 {
+		t.Fatalf("expected expired key to report not loaded, got (%v, %v)", old, loaded)
+	}
+
+	if v, err := m.Get("a"); err != nil || v != 42 {
+		t.Fatalf("expected swapped-in value to be immediately visible, got (%v, %v)", v, err)
+	}
+}
+
+func TestSyncMapCompareAndSwapExpired(t *testing.T) {
+	m := New(map[string]int{})
+	m.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+	time.Sleep(20 * time.Millisecond)
+
+	if m.CompareAndSwap("a", 1, 42) {
+		t.Fatal("expected CompareAndSwap to fail against an expired entry")
+	}
+	if m.Contains("a") {
+		t.Fatal("expected expired entry to have been reaped")
+	}
+}
+
+func TestSyncMapCompareAndDeleteExpired(t *testing.T) {
+	m := New(map[string]int{})
+	m.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+	time.Sleep(20 * time.Millisecond)
+
+	if m.CompareAndDelete("a", 1) {
+		t.Fatal("expected CompareAndDelete to fail against an expired entry")
+	}
+}
+
+func TestSyncMapLoadOrStoreExpired(t *testing.T) {
+	m := New(map[string]int{})
+	m.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+	time.Sleep(20 * time.Millisecond)
+
+	v, loaded := m.LoadOrStore("a", 42)
+	if loaded || v != 42 {
+		t.Fatalf("expected expired key to be overwritten, got (%v, %v)", v, loaded)
+	}
+
+	if got, err := m.Get("a"); err != nil || got != 42 {
+		t.Fatalf("unexpected value after LoadOrStore on expired key: (%v, %v)", got, err)
+	}
+}
+
+func TestSyncMapLoadAndDeleteExpired(t *testing.T) {
+	m := New(map[string]int{})
+	m.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := m.LoadAndDelete("a"); ok {
+		t.Fatal("expected expired key to report not loaded")
+	}
+}
+
+func TestSyncMapBytesWithTTL(t *testing.T) {
+	c1 := New(map[string]int{})
+	c1.InsertWithTTL("a", 1, time.Hour, false)
+	c1.Insert("b", 2, false)
+
+	b, err := c1.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error during Bytes(): %v", err)
+	}
+
+	c2 := New(map[string]int{})
+	if err := c2.Merge(b); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	if v, err := c2.Get("a"); err != nil || v != 1 {
+		t.Fatalf("unexpected result for TTL entry (%v, %v)", v, err)
+	}
+
+	// The restored expiry must still be enforced going forward.
+	c3 := New(map[string]int{})
+	c3.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+	b2, err := c3.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error during Bytes(): %v", err)
+	}
+
+	c4 := New(map[string]int{})
+	if err := c4.Merge(b2); err != nil {
+		t.Fatalf("unexpected error during Merge(): %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c4.Get("a"); err == nil {
+		t.Fatal("expected restored TTL to still be enforced after Merge")
+	}
+}
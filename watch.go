@@ -0,0 +1,189 @@
+package syncmap
+
+import "sync"
+
+// Op identifies the kind of change a watcher is being notified about
+type Op int
+
+const (
+	// OpInsert is fired when a key that was not previously present is added
+	OpInsert Op = iota
+	// OpUpdate is fired when the value at an existing key is replaced
+	OpUpdate
+	// OpRemove is fired when a key is deleted, whether explicitly or as
+	// the losing side of a CompareAndDelete/LoadAndDelete
+	OpRemove
+	// OpExpire is fired when a key is reaped after its TTL elapses,
+	// whether lazily on Get/Contains or by the background reaper
+	OpExpire
+)
+
+// Event describes a single change to a SynchronisedMap.  Seq is a
+// monotonically increasing counter, also returned by Snapshot, that lets
+// a new watcher bootstrap from a Snapshot without missing or
+// double-applying events: discard any Event whose Seq is not greater
+// than the offset returned alongside the Snapshot it bootstrapped from.
+type Event[T ComparableAndOrdered, U any] struct {
+	Seq uint64
+	Op  Op
+	Key T
+	Old U
+	New U
+}
+
+// WatchPolicy controls what happens when a watcher's channel is full
+type WatchPolicy int
+
+const (
+	// WatchPolicyDropNewest discards the incoming event for any watcher
+	// whose channel is currently full, rather than blocking the
+	// goroutine that made the change.  Watchers that fall behind should
+	// notice the gap via Seq and re-synchronise with Snapshot.
+	WatchPolicyDropNewest WatchPolicy = iota
+	// WatchPolicyBlock blocks the goroutine making a change until every
+	// watcher's channel has room, guaranteeing delivery at the cost of
+	// letting a slow watcher apply backpressure to the whole map.
+	WatchPolicyBlock
+)
+
+// DefaultWatchBufferSize is the per-watcher channel buffer size used
+// when WithWatchBufferSize is not supplied
+const DefaultWatchBufferSize = 256
+
+// subscriber holds the state backing a single Watch channel. done is
+// closed by Unwatch to interrupt a send that is blocked under
+// WatchPolicyBlock; mu serialises sends against Unwatch closing ch, so
+// ch is never closed while notify might still be sending on it - it is
+// unsafe in Go for a channel to be closed concurrently with a send.
+type subscriber[T ComparableAndOrdered, U any] struct {
+	ch     chan Event[T, U]
+	done   chan struct{}
+	mu     sync.Mutex
+	closed bool
+}
+
+// WithWatchPolicy selects the policy applied when a watcher's channel is
+// full.  If not supplied, WatchPolicyDropNewest is used.
+func WithWatchPolicy[T ComparableAndOrdered, U any](p WatchPolicy) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.watchPolicy = p
+	}
+}
+
+// WithWatchBufferSize sets the buffer size of the channel returned by
+// Watch.  If not supplied, DefaultWatchBufferSize is used.
+func WithWatchBufferSize[T ComparableAndOrdered, U any](n int) Option[T, U] {
+	return func(m *SynchronisedMap[T, U]) {
+		m.watchBufferSize = n
+	}
+}
+
+// Watch registers a new subscriber and returns a channel of Events
+// describing subsequent changes to the map.  Call Unwatch with the same
+// channel to stop receiving events and release its resources.
+func (s *SynchronisedMap[T, U]) Watch() <-chan Event[T, U] {
+	ch := make(chan Event[T, U], s.watchBufferSize)
+	sub := &subscriber[T, U]{ch: ch, done: make(chan struct{})}
+
+	s.watchLck.Lock()
+	defer s.watchLck.Unlock()
+
+	s.watchers[ch] = sub
+	return ch
+}
+
+// Unwatch unregisters a channel previously returned by Watch, and closes
+// it.  It is a no-op if ch is not currently registered.  A watcher that
+// is not draining its channel under WatchPolicyBlock does not prevent
+// Unwatch from completing: it interrupts any in-flight blocked send
+// before closing the channel, rather than blocking alongside it.
+func (s *SynchronisedMap[T, U]) Unwatch(ch <-chan Event[T, U]) {
+	s.watchLck.Lock()
+	sub, ok := s.watchers[ch]
+	delete(s.watchers, ch)
+	s.watchLck.Unlock()
+
+	if !ok {
+		return
+	}
+
+	close(sub.done)
+
+	sub.mu.Lock()
+	sub.closed = true
+	close(sub.ch)
+	sub.mu.Unlock()
+}
+
+// Snapshot returns a consistent copy of the map's current contents,
+// alongside the Seq of the last change reflected in it.  A new watcher
+// can combine this with Watch to bootstrap without missing events: see
+// Event.
+func (s *SynchronisedMap[T, U]) Snapshot() (map[T]U, uint64) {
+	s.lck.RLock()
+	defer s.lck.RUnlock()
+
+	m := make(map[T]U, len(s.m))
+	for k, v := range s.m {
+		m[k] = v
+	}
+
+	return m, s.seq.Load()
+}
+
+// eventLocked builds the Event for a change, assigning it the next Seq.
+// Callers must hold s.lck for writing.
+func (s *SynchronisedMap[T, U]) eventLocked(op Op, k T, old, new U) Event[T, U] {
+	return Event[T, U]{
+		Seq: s.seq.Add(1),
+		Op:  op,
+		Key: k,
+		Old: old,
+		New: new,
+	}
+}
+
+// notify fans ev out to every current watcher, outside of s.lck. The
+// list of subscribers is copied under watchLck and then released before
+// sending, so a slow or stuck watcher under WatchPolicyBlock blocks only
+// goroutines delivering events to that watcher (each waits on sub.mu in
+// turn), never Unwatch, s.lck or watchLck.
+func (s *SynchronisedMap[T, U]) notify(ev Event[T, U]) {
+	s.watchLck.RLock()
+	subs := make([]*subscriber[T, U], 0, len(s.watchers))
+	for _, sub := range s.watchers {
+		subs = append(subs, sub)
+	}
+	s.watchLck.RUnlock()
+
+	for _, sub := range subs {
+		s.send(sub, ev)
+	}
+}
+
+// send delivers ev to sub per the map's WatchPolicy. It holds sub.mu for
+// the duration of the attempt, which Unwatch also takes before closing
+// sub.ch, so send never operates on a channel that is concurrently being
+// closed: once Unwatch has closed sub.done and taken sub.mu, sub.closed
+// is observed true and ch is left untouched.
+func (s *SynchronisedMap[T, U]) send(sub *subscriber[T, U], ev Event[T, U]) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+
+	if s.watchPolicy == WatchPolicyBlock {
+		select {
+		case sub.ch <- ev:
+		case <-sub.done:
+		}
+		return
+	}
+
+	select {
+	case sub.ch <- ev:
+	default:
+	}
+}
@@ -0,0 +1,151 @@
+package syncmap
+
+import (
+	"testing"
+	"time"
+)
+
+func waitForEvent[T ComparableAndOrdered, U any](t *testing.T, ch <-chan Event[T, U]) Event[T, U] {
+	t.Helper()
+	select {
+	case ev := <-ch:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for watch event")
+		panic("unreachable")
+	}
+}
+
+func TestSyncMapWatchInsertUpdateRemove(t *testing.T) {
+	m := New(map[string]int{})
+	ch := m.Watch()
+	defer m.Unwatch(ch)
+
+	m.Insert("a", 1, false)
+	ev := waitForEvent(t, ch)
+	if ev.Op != OpInsert || ev.Key != "a" || ev.New != 1 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	m.Insert("a", 2, false)
+	ev = waitForEvent(t, ch)
+	if ev.Op != OpUpdate || ev.Old != 1 || ev.New != 2 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+
+	m.Remove("a")
+	ev = waitForEvent(t, ch)
+	if ev.Op != OpRemove || ev.Old != 2 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestSyncMapWatchExpire(t *testing.T) {
+	m := New(map[string]int{})
+	ch := m.Watch()
+	defer m.Unwatch(ch)
+
+	m.InsertWithTTL("a", 1, 10*time.Millisecond, false)
+	waitForEvent(t, ch) // the insert itself
+
+	time.Sleep(20 * time.Millisecond)
+	m.Contains("a") // triggers lazy reap
+
+	ev := waitForEvent(t, ch)
+	if ev.Op != OpExpire || ev.Key != "a" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestSyncMapUnwatch(t *testing.T) {
+	m := New(map[string]int{})
+	ch := m.Watch()
+	m.Unwatch(ch)
+
+	m.Insert("a", 1, false)
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel to be closed after Unwatch")
+	}
+}
+
+func TestSyncMapSnapshotAndWatch(t *testing.T) {
+	m := New(map[string]int{"a": 1})
+
+	ch := m.Watch()
+	defer m.Unwatch(ch)
+
+	m.Insert("b", 2, false)
+
+	data, offset := m.Snapshot()
+
+	// Replay any events not yet reflected in the snapshot.
+	applied := map[string]int{}
+	for k, v := range data {
+		applied[k] = v
+	}
+	for {
+		select {
+		case ev := <-ch:
+			if ev.Seq > offset {
+				applied[ev.Key] = ev.New
+			}
+		default:
+			if applied["a"] != 1 || applied["b"] != 2 {
+				t.Fatalf("unexpected reconstructed state: %v", applied)
+			}
+			return
+		}
+	}
+}
+
+func TestSyncMapUnwatchStuckBlockingWatcher(t *testing.T) {
+	m := New(map[string]int{}, WithWatchPolicy[string, int](WatchPolicyBlock), WithWatchBufferSize[string, int](1))
+	ch := m.Watch()
+
+	// Fill the buffer, then leave it undrained: any further notify for
+	// this watcher now blocks under WatchPolicyBlock.
+	m.Insert("a", 1, false)
+
+	done := make(chan struct{})
+	go func() {
+		m.Insert("b", 2, false) // blocks until Unwatch interrupts it
+		close(done)
+	}()
+
+	m.Unwatch(ch)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Insert did not unblock after Unwatch of a stuck watcher")
+	}
+
+	waitForEvent(t, ch) // the buffered "a" insert, still queued
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel to be closed after Unwatch")
+	}
+}
+
+func TestSyncMapWatchDropNewestPolicy(t *testing.T) {
+	m := New(map[string]int{}, WithWatchBufferSize[string, int](1))
+	ch := m.Watch()
+	defer m.Unwatch(ch)
+
+	m.Insert("a", 1, false)
+	m.Insert("b", 2, false)
+	m.Insert("c", 3, false)
+
+	// Only one event fits in the buffer; the rest are dropped rather
+	// than blocking the inserts above.
+	ev := waitForEvent(t, ch)
+	if ev.Op != OpInsert {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	select {
+	case ev := <-ch:
+		t.Fatalf("expected no further buffered events, got %+v", ev)
+	default:
+	}
+}